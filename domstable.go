@@ -0,0 +1,169 @@
+package chromedp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/network"
+)
+
+type stableOptions struct {
+	timeout          time.Duration
+	networkIdle      bool
+	networkIdleConns int
+	networkIdleTime  time.Duration
+	mutations        *int
+}
+
+// StableOption is a WaitDOMStable action option.
+type StableOption = func(*stableOptions)
+
+// WithStableTimeout sets the maximum time WaitDOMStable will wait for the
+// page to settle before giving up with an error. The default is 30 seconds.
+func WithStableTimeout(timeout time.Duration) StableOption {
+	return func(opts *stableOptions) {
+		opts.timeout = timeout
+	}
+}
+
+// WithNetworkIdle additionally requires that no more than maxConns requests
+// are in flight for idleTime before WaitDOMStable returns.
+func WithNetworkIdle(maxConns int, idleTime time.Duration) StableOption {
+	return func(opts *stableOptions) {
+		opts.networkIdle = true
+		opts.networkIdleConns = maxConns
+		opts.networkIdleTime = idleTime
+	}
+}
+
+// WithMutationCount reports the number of DOM mutation events observed while
+// waiting into count.
+func WithMutationCount(count *int) StableOption {
+	return func(opts *stableOptions) {
+		opts.mutations = count
+	}
+}
+
+// WaitDOMStable blocks until no DOM.documentUpdated, DOM.childNodeInserted,
+// DOM.childNodeRemoved, or DOM.attributeModified event has been observed for
+// quietPeriod, optionally also requiring the network to have gone idle. This
+// is meant to replace a fixed chromedp.Sleep after navigating to a page
+// whose content is still being rendered by client-side JavaScript well after
+// the load event fires.
+//
+// sel must already be present in the document; WaitDOMStable does not wait
+// for it to appear. CDP reports DOM mutations for the whole document, not
+// per-selector, so sel is only used to confirm the watched subtree exists.
+func WaitDOMStable(sel string, quietPeriod time.Duration, opts ...StableOption) Action {
+	options := &stableOptions{timeout: 30 * time.Second}
+	for _, o := range opts {
+		o(options)
+	}
+
+	return ActionFunc(func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, options.timeout)
+		defer cancel()
+
+		root, err := dom.GetDocument().Do(ctx)
+		if err != nil {
+			return err
+		}
+		nodeID, err := dom.QuerySelector(root.NodeID, sel).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if nodeID == cdp.EmptyNodeID {
+			return fmt.Errorf("node %q not found", sel)
+		}
+
+		mutated := make(chan struct{}, 1)
+		inflight := make(map[network.RequestID]struct{})
+		var inflightMu sync.Mutex
+		netIdle := make(chan struct{}, 1)
+
+		ListenTarget(ctx, func(ev interface{}) {
+			switch ev.(type) {
+			case *dom.EventDocumentUpdated,
+				*dom.EventChildNodeInserted,
+				*dom.EventChildNodeRemoved,
+				*dom.EventAttributeModified:
+				if options.mutations != nil {
+					*options.mutations++
+				}
+				select {
+				case mutated <- struct{}{}:
+				default:
+				}
+			}
+
+			if !options.networkIdle {
+				return
+			}
+			switch e := ev.(type) {
+			case *network.EventRequestWillBeSent:
+				inflightMu.Lock()
+				inflight[e.RequestID] = struct{}{}
+				inflightMu.Unlock()
+			case *network.EventLoadingFinished:
+				inflightMu.Lock()
+				delete(inflight, e.RequestID)
+				n := len(inflight)
+				inflightMu.Unlock()
+				if n <= options.networkIdleConns {
+					select {
+					case netIdle <- struct{}{}:
+					default:
+					}
+				}
+			case *network.EventLoadingFailed:
+				inflightMu.Lock()
+				delete(inflight, e.RequestID)
+				n := len(inflight)
+				inflightMu.Unlock()
+				if n <= options.networkIdleConns {
+					select {
+					case netIdle <- struct{}{}:
+					default:
+					}
+				}
+			}
+		})
+
+		quiet := time.NewTimer(quietPeriod)
+		defer quiet.Stop()
+
+		// A page with no requests outstanding by the time the listener
+		// attaches is already network-idle; don't wait for a
+		// loadingFinished event that may never come to say so.
+		netIdleSince := time.Now()
+		for {
+			select {
+			case <-mutated:
+				if !quiet.Stop() {
+					<-quiet.C
+				}
+				quiet.Reset(quietPeriod)
+			case <-netIdle:
+				netIdleSince = time.Now()
+			case <-quiet.C:
+				if !options.networkIdle {
+					return nil
+				}
+				inflightMu.Lock()
+				n := len(inflight)
+				inflightMu.Unlock()
+				if n <= options.networkIdleConns &&
+					time.Since(netIdleSince) >= options.networkIdleTime {
+					return nil
+				}
+				quiet.Reset(quietPeriod)
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	})
+}
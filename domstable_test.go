@@ -0,0 +1,30 @@
+package chromedp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStableOptionsOverrideDefaults(t *testing.T) {
+	var count int
+	options := &stableOptions{timeout: 30 * time.Second}
+
+	for _, o := range []StableOption{
+		WithStableTimeout(5 * time.Second),
+		WithNetworkIdle(2, time.Second),
+		WithMutationCount(&count),
+	} {
+		o(options)
+	}
+
+	if options.timeout != 5*time.Second {
+		t.Fatalf("got timeout %v, want 5s", options.timeout)
+	}
+	if !options.networkIdle || options.networkIdleConns != 2 || options.networkIdleTime != time.Second {
+		t.Fatalf("got networkIdle=%v conns=%d time=%v, want true 2 1s",
+			options.networkIdle, options.networkIdleConns, options.networkIdleTime)
+	}
+	if options.mutations != &count {
+		t.Fatalf("WithMutationCount did not wire the provided pointer through")
+	}
+}
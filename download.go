@@ -0,0 +1,267 @@
+package chromedp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/network"
+)
+
+// DownloadInfo describes the outcome of a single browser download, as
+// reported via the Browser.downloadWillBegin and Browser.downloadProgress
+// events. MIMEType is correlated from the Network.responseReceived event for
+// the download's URL, and is empty if that event wasn't observed first (for
+// example, if the response came from cache).
+type DownloadInfo struct {
+	GUID     string
+	URL      string
+	MIMEType string
+	State    browser.DownloadProgressState
+	Path     string
+}
+
+type downloadOptions struct {
+	behavior browser.SetDownloadBehaviorBehavior
+}
+
+// DownloadOption is a HandleDownloads action option.
+type DownloadOption = func(*downloadOptions)
+
+// WithDownloadBehavior sets the Browser.setDownloadBehavior behavior used by
+// HandleDownloads. The default is BehaviorAllowAndName, which saves each file
+// under its GUID; DownloadInfo.Path and WaitDownload's rename both assume
+// GUID-named files.
+func WithDownloadBehavior(behavior browser.SetDownloadBehaviorBehavior) DownloadOption {
+	return func(opts *downloadOptions) {
+		opts.behavior = behavior
+	}
+}
+
+// download tracks the state needed to resolve a download's final path once
+// it completes, keyed by its GUID.
+type download struct {
+	dir               string
+	url               string
+	mimeType          string
+	suggestedFilename string
+	done              chan *DownloadInfo
+}
+
+type downloadsKey struct{}
+
+// downloadRegistry holds in-flight and completed downloads for a single
+// HandleDownloads call, keyed by GUID. It's threaded through context (the
+// way target.go's attachedTarget is) rather than kept in a package-level
+// variable, so it's scoped to - and garbage collected along with - the
+// browser context it belongs to, instead of leaking across unrelated ones.
+type downloadRegistry struct {
+	mu        sync.Mutex
+	entries   map[string]*download
+	mimeTypes map[string]string // by URL, from Network.responseReceived
+}
+
+func newDownloadRegistry() *downloadRegistry {
+	return &downloadRegistry{
+		entries:   make(map[string]*download),
+		mimeTypes: make(map[string]string),
+	}
+}
+
+// recordMIMEType remembers the MIME type of a response, so it can be
+// attached to a download for the same URL that begins afterwards.
+func (r *downloadRegistry) recordMIMEType(url, mimeType string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mimeTypes[url] = mimeType
+}
+
+func (r *downloadRegistry) mimeType(url string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.mimeTypes[url]
+}
+
+func (r *downloadRegistry) entry(guid string) *download {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d, ok := r.entries[guid]
+	if !ok {
+		d = &download{done: make(chan *DownloadInfo, 1)}
+		r.entries[guid] = d
+	}
+	return d
+}
+
+func (r *downloadRegistry) delete(guid string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, guid)
+}
+
+func (r *downloadRegistry) clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = make(map[string]*download)
+	r.mimeTypes = make(map[string]string)
+}
+
+func downloadRegistryFromContext(ctx context.Context) (*downloadRegistry, bool) {
+	r, ok := ctx.Value(downloadsKey{}).(*downloadRegistry)
+	return r, ok
+}
+
+// downloadsContext is a context.Context that starts out empty and is filled
+// in once HandleDownloads' Action has registered its downloadRegistry, so
+// that HandleDownloads can hand back a context before that registration
+// happens - the same pattern NewTab uses for its own deferred attachment.
+type downloadsContext struct {
+	mu       sync.Mutex
+	resolved context.Context
+}
+
+func (d *downloadsContext) set(ctx context.Context) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.resolved = ctx
+}
+
+func (d *downloadsContext) get() context.Context {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.resolved == nil {
+		return context.Background()
+	}
+	return d.resolved
+}
+
+func (d *downloadsContext) Deadline() (deadline time.Time, ok bool) { return d.get().Deadline() }
+func (d *downloadsContext) Done() <-chan struct{}                   { return d.get().Done() }
+func (d *downloadsContext) Err() error                              { return d.get().Err() }
+func (d *downloadsContext) Value(key interface{}) interface{}       { return d.get().Value(key) }
+
+// HandleDownloads enables download events, directs downloads into dir, and
+// sends a DownloadInfo on infos for every download that reaches a terminal
+// state (completed or canceled). It installs its listener for the lifetime
+// of ctx, so it is typically run once via chromedp.Run alongside the rest of
+// a task list, ahead of whatever action triggers the download. It returns a
+// context scoped to its download registry; pass that context to WaitDownload
+// rather than the original one.
+func HandleDownloads(dir string, infos chan<- *DownloadInfo, opts ...DownloadOption) (context.Context, Action) {
+	options := &downloadOptions{behavior: browser.SetDownloadBehaviorBehaviorAllowAndName}
+	for _, o := range opts {
+		o(options)
+	}
+
+	registry := newDownloadRegistry()
+	scoped := &downloadsContext{}
+
+	act := ActionFunc(func(ctx context.Context) error {
+		err := browser.SetDownloadBehavior(options.behavior).
+			WithDownloadPath(dir).
+			WithEventsEnabled(true).
+			Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		scoped.set(context.WithValue(ctx, downloadsKey{}, registry))
+
+		ListenTarget(ctx, func(ev interface{}) {
+			switch e := ev.(type) {
+			case *network.EventResponseReceived:
+				registry.recordMIMEType(e.Response.URL, e.Response.MimeType)
+
+			case *browser.EventDownloadWillBegin:
+				d := registry.entry(e.GUID)
+				d.dir = dir
+				d.url = e.URL
+				d.suggestedFilename = e.SuggestedFilename
+				d.mimeType = registry.mimeType(e.URL)
+
+			case *browser.EventDownloadProgress:
+				if e.State != browser.DownloadProgressStateCompleted &&
+					e.State != browser.DownloadProgressStateCanceled {
+					return
+				}
+
+				d := registry.entry(e.GUID)
+				info := &DownloadInfo{
+					GUID:     e.GUID,
+					URL:      d.url,
+					MIMEType: d.mimeType,
+					State:    e.State,
+				}
+				if e.State == browser.DownloadProgressStateCompleted {
+					info.Path = filepath.Join(dir, e.GUID)
+				}
+
+				select {
+				case d.done <- info:
+				default:
+				}
+				if infos != nil {
+					select {
+					case infos <- info:
+					case <-ctx.Done():
+					}
+				}
+			}
+		})
+
+		go func() {
+			<-ctx.Done()
+			registry.clear()
+		}()
+
+		return nil
+	})
+
+	return scoped, act
+}
+
+// WaitDownload blocks until the download identified by guid reaches the
+// completed state, then renames the downloaded file from its GUID to its
+// suggested filename. ctx must be the context returned by the HandleDownloads
+// call that's tracking guid, and guid must be one it has reported - via a
+// DownloadInfo on its infos channel, or the current frame's
+// EventDownloadWillBegin.
+func WaitDownload(guid string, path *string) Action {
+	return ActionFunc(func(ctx context.Context) error {
+		registry, ok := downloadRegistryFromContext(ctx)
+		if !ok {
+			return fmt.Errorf("WaitDownload: ctx is not scoped to a HandleDownloads call")
+		}
+		d := registry.entry(guid)
+
+		select {
+		case info := <-d.done:
+			if info.State != browser.DownloadProgressStateCompleted {
+				registry.delete(guid)
+				return fmt.Errorf("download %s did not complete: %s", guid, info.State)
+			}
+
+			newPath := d.suggestedFilename
+			if newPath == "" {
+				newPath = guid
+			}
+			newPath = filepath.Join(d.dir, newPath)
+			if err := os.Rename(info.Path, newPath); err != nil {
+				return err
+			}
+
+			registry.delete(guid)
+
+			if path != nil {
+				*path = newPath
+			}
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package chromedp
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDownloadRegistryEntryIsStableAndDeletable(t *testing.T) {
+	r := newDownloadRegistry()
+
+	d1 := r.entry("guid-1")
+	d2 := r.entry("guid-1")
+	if d1 != d2 {
+		t.Fatalf("entry() returned different *download for the same GUID")
+	}
+
+	r.delete("guid-1")
+	d3 := r.entry("guid-1")
+	if d3 == d1 {
+		t.Fatalf("entry() after delete() returned the stale *download instead of a fresh one")
+	}
+}
+
+func TestDownloadRegistryClearRemovesAllEntries(t *testing.T) {
+	r := newDownloadRegistry()
+	r.entry("guid-1")
+	r.entry("guid-2")
+
+	r.clear()
+
+	r.mu.Lock()
+	n := len(r.entries)
+	r.mu.Unlock()
+	if n != 0 {
+		t.Fatalf("got %d entries after clear(), want 0", n)
+	}
+}
+
+func TestDownloadRegistryRecordsMIMETypeByURL(t *testing.T) {
+	r := newDownloadRegistry()
+
+	if got := r.mimeType("https://example.com/file.zip"); got != "" {
+		t.Fatalf("got mimeType %q before any response was recorded, want empty", got)
+	}
+
+	r.recordMIMEType("https://example.com/file.zip", "application/zip")
+	if got := r.mimeType("https://example.com/file.zip"); got != "application/zip" {
+		t.Fatalf("got mimeType %q, want application/zip", got)
+	}
+
+	r.clear()
+	if got := r.mimeType("https://example.com/file.zip"); got != "" {
+		t.Fatalf("got mimeType %q after clear(), want empty", got)
+	}
+}
+
+func TestDownloadRegistryFromContextRoundTrips(t *testing.T) {
+	if _, ok := downloadRegistryFromContext(context.Background()); ok {
+		t.Fatalf("found a registry in a plain context.Background()")
+	}
+
+	r := newDownloadRegistry()
+	ctx := context.WithValue(context.Background(), downloadsKey{}, r)
+
+	got, ok := downloadRegistryFromContext(ctx)
+	if !ok || got != r {
+		t.Fatalf("downloadRegistryFromContext did not round-trip the registry stored via downloadsKey")
+	}
+}
@@ -0,0 +1,152 @@
+package chromedp
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	cdpfetch "github.com/chromedp/cdproto/fetch"
+)
+
+// fetchDisableTimeout bounds the best-effort Fetch.disable call Fetch makes
+// once its ctx is done; by then the session that ctx belongs to may already
+// be tearing down, so this shouldn't block shutdown for long.
+const fetchDisableTimeout = 2 * time.Second
+
+// FetchAction is the decision a Fetch handler makes about a single
+// intercepted request.
+type FetchAction interface {
+	do(ctx context.Context, requestID cdpfetch.RequestID) error
+}
+
+type fetchActionFunc func(ctx context.Context, requestID cdpfetch.RequestID) error
+
+func (f fetchActionFunc) do(ctx context.Context, requestID cdpfetch.RequestID) error {
+	return f(ctx, requestID)
+}
+
+// Continue lets the intercepted request proceed unmodified.
+func Continue() FetchAction {
+	return fetchActionFunc(func(ctx context.Context, requestID cdpfetch.RequestID) error {
+		return cdpfetch.ContinueRequest(requestID).Do(ctx)
+	})
+}
+
+// ModifyRequest continues the intercepted request, overriding whichever of
+// url, method, postData and headers are non-empty.
+func ModifyRequest(url, method, postData string, headers http.Header) FetchAction {
+	return fetchActionFunc(func(ctx context.Context, requestID cdpfetch.RequestID) error {
+		req := cdpfetch.ContinueRequest(requestID)
+		if url != "" {
+			req = req.WithURL(url)
+		}
+		if method != "" {
+			req = req.WithMethod(method)
+		}
+		if postData != "" {
+			// Fetch.continueRequest's postData parameter must be base64-encoded.
+			req = req.WithPostData(base64.StdEncoding.EncodeToString([]byte(postData)))
+		}
+		if len(headers) > 0 {
+			req = req.WithHeaders(headersToFetchHeaders(headers))
+		}
+		return req.Do(ctx)
+	})
+}
+
+// Fail aborts the intercepted request with the given network error reason.
+func Fail(reason cdpfetch.ErrorReason) FetchAction {
+	return fetchActionFunc(func(ctx context.Context, requestID cdpfetch.RequestID) error {
+		return cdpfetch.FailRequest(requestID, reason).Do(ctx)
+	})
+}
+
+// Fulfill short-circuits the intercepted request, returning status, headers
+// and body to the page as if they came from the network.
+func Fulfill(status int64, headers http.Header, body []byte) FetchAction {
+	return fetchActionFunc(func(ctx context.Context, requestID cdpfetch.RequestID) error {
+		return fulfillParams(requestID, status, headers, body).Do(ctx)
+	})
+}
+
+// fulfillParams builds the Fetch.fulfillRequest params for Fulfill,
+// separated out so the body-encoding it does can be unit tested without a
+// live CDP session.
+func fulfillParams(requestID cdpfetch.RequestID, status int64, headers http.Header, body []byte) *cdpfetch.FulfillRequestParams {
+	// Fetch.fulfillRequest's body parameter must be base64-encoded.
+	encodedBody := base64.StdEncoding.EncodeToString(body)
+	return cdpfetch.FulfillRequest(requestID, status).
+		WithResponseHeaders(headersToFetchHeaders(headers)).
+		WithBody(encodedBody)
+}
+
+// ProvideResponse is an alias for Fulfill, named to match the CDP
+// Fetch.continueWithAuth/fulfillRequest terminology used by other
+// automation libraries' request interception APIs.
+func ProvideResponse(status int64, headers http.Header, body []byte) FetchAction {
+	return Fulfill(status, headers, body)
+}
+
+func headersToFetchHeaders(headers http.Header) []*cdpfetch.HeaderEntry {
+	entries := make([]*cdpfetch.HeaderEntry, 0, len(headers))
+	for name, values := range headers {
+		for _, value := range values {
+			entries = append(entries, &cdpfetch.HeaderEntry{Name: name, Value: value})
+		}
+	}
+	return entries
+}
+
+// Fetch enables request interception for patterns (or every request, if
+// patterns is empty) and installs handler for the lifetime of ctx. handler
+// is called once per Fetch.requestPaused event, in its own goroutine, and
+// must return a FetchAction describing how to resolve it. If resolving the
+// returned FetchAction itself fails - for example, the session tearing down
+// mid-request - the request is failed outright rather than left paused with
+// no way for the caller to observe it. Fetch.enable is called when the
+// action runs, and a background goroutine calls Fetch.disable once ctx is
+// done, so interception doesn't outlive it. This lets a task list implement
+// HAR-style replay, ad blocking, or deterministic fixtures directly on top
+// of the Fetch domain.
+func Fetch(patterns []*cdpfetch.RequestPattern, handler func(ctx context.Context, ev *cdpfetch.EventRequestPaused) FetchAction) Action {
+	return ActionFunc(func(ctx context.Context) error {
+		err := cdpfetch.Enable().WithPatterns(patterns).Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		ListenTarget(ctx, func(ev interface{}) {
+			e, ok := ev.(*cdpfetch.EventRequestPaused)
+			if !ok {
+				return
+			}
+
+			go func() {
+				action := handler(ctx, e)
+				if action == nil {
+					action = Continue()
+				}
+				if err := action.do(ctx, e.RequestID); err != nil {
+					// The chosen action's own CDP command failed - leaving
+					// the request paused in the browser with no way for the
+					// caller to observe it. Fail it explicitly so the page
+					// sees a network error instead of hanging forever.
+					cdpfetch.FailRequest(e.RequestID, cdpfetch.ErrorReasonFailed).Do(ctx)
+				}
+			}()
+		})
+
+		executor := cdp.ExecutorFromContext(ctx)
+		go func() {
+			<-ctx.Done()
+
+			disableCtx, cancel := context.WithTimeout(cdp.WithExecutor(context.Background(), executor), fetchDisableTimeout)
+			defer cancel()
+			cdpfetch.Disable().Do(disableCtx)
+		}()
+
+		return nil
+	})
+}
@@ -0,0 +1,45 @@
+package chromedp
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	cdpfetch "github.com/chromedp/cdproto/fetch"
+)
+
+func TestHeadersToFetchHeaders(t *testing.T) {
+	headers := http.Header{
+		"X-Foo": []string{"bar", "baz"},
+	}
+
+	entries := headersToFetchHeaders(headers)
+	if len(entries) != 2 {
+		t.Fatalf("got %d header entries, want 2", len(entries))
+	}
+
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if e.Name != "X-Foo" {
+			t.Fatalf("got header name %q, want X-Foo", e.Name)
+		}
+		seen[e.Value] = true
+	}
+	if !seen["bar"] || !seen["baz"] {
+		t.Fatalf("got values %v, want bar and baz", seen)
+	}
+}
+
+func TestFulfillParamsEncodesBodyAsBase64(t *testing.T) {
+	body := []byte("not valid base64 on its own: \x00\x01binary")
+
+	params := fulfillParams(cdpfetch.RequestID("req1"), 200, nil, body)
+
+	decoded, err := base64.StdEncoding.DecodeString(params.Body)
+	if err != nil {
+		t.Fatalf("params.Body is not base64: %v", err)
+	}
+	if string(decoded) != string(body) {
+		t.Fatalf("got decoded body %q, want %q", decoded, body)
+	}
+}
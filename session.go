@@ -0,0 +1,154 @@
+package chromedp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/indexeddb"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/storage"
+)
+
+// sessionVersion is the envelope format written by SaveSession. Bump it
+// whenever the Session struct's on-disk shape changes.
+const sessionVersion = 1
+
+// Session is the serialized snapshot of a browser's cookies and per-origin
+// storage, as produced by SaveSession and consumed by LoadSession.
+type Session struct {
+	Version int               `json:"version"`
+	Cookies []*network.Cookie `json:"cookies"`
+	Origins []*OriginSession  `json:"origins"`
+}
+
+// OriginSession holds the local storage entries and IndexedDB database names
+// captured for a single origin.
+type OriginSession struct {
+	Origin         string     `json:"origin"`
+	StorageKey     string     `json:"storageKey"`
+	LocalStorage   [][]string `json:"localStorage,omitempty"`
+	IndexedDBNames []string   `json:"indexedDBNames,omitempty"`
+}
+
+type sessionOptions struct {
+	includeIndexedDB bool
+}
+
+// SessionOption is a SaveSession/LoadSession action option.
+type SessionOption = func(*sessionOptions)
+
+// WithoutIndexedDB skips capturing IndexedDB database names, which requires
+// enumerating every origin and can be slow on sites that use many databases.
+func WithoutIndexedDB(opts *sessionOptions) {
+	opts.includeIndexedDB = false
+}
+
+// SaveSession captures the browser's cookies plus local storage and
+// IndexedDB database names for every frame's origin, and writes them to w as
+// JSON. Pair with LoadSession to let a script log in once interactively and
+// resume headless runs from the same session thereafter, without relying on
+// Chrome's on-disk profile directory.
+func SaveSession(w io.Writer, opts ...SessionOption) Action {
+	options := &sessionOptions{includeIndexedDB: true}
+	for _, o := range opts {
+		o(options)
+	}
+
+	return ActionFunc(func(ctx context.Context) error {
+		cookies, err := network.GetAllCookies().Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		tree, err := page.GetFrameTree().Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		sess := &Session{Version: sessionVersion, Cookies: cookies}
+		seen := map[string]bool{}
+		for _, frame := range collectFrames(tree) {
+			key, err := storage.GetStorageKeyForFrame(frame.ID).Do(ctx)
+			if err != nil || seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			items, err := storage.GetDOMStorageItems(&storage.StorageID{
+				StorageKey:     key,
+				IsLocalStorage: true,
+			}).Do(ctx)
+			if err != nil {
+				continue
+			}
+
+			os := &OriginSession{Origin: frame.SecurityOrigin, StorageKey: key, LocalStorage: items}
+
+			if options.includeIndexedDB {
+				if names, err := indexeddb.RequestDatabaseNames().WithStorageKey(key).Do(ctx); err == nil {
+					os.IndexedDBNames = names
+				}
+			}
+
+			sess.Origins = append(sess.Origins, os)
+		}
+
+		return json.NewEncoder(w).Encode(sess)
+	})
+}
+
+// collectFrames flattens a frame tree into the list of frames it contains,
+// since DOM storage and IndexedDB are captured per-origin by resolving each
+// frame to its storage key and security origin.
+func collectFrames(node *page.FrameTree) (frames []*cdp.Frame) {
+	if node == nil || node.Frame == nil {
+		return nil
+	}
+	frames = append(frames, node.Frame)
+	for _, child := range node.ChildFrames {
+		frames = append(frames, collectFrames(child)...)
+	}
+	return frames
+}
+
+// LoadSession restores cookies and local storage previously captured by
+// SaveSession. It does not restore IndexedDB contents, since CDP has no
+// write path for IndexedDB records; IndexedDBNames is carried through purely
+// as a diagnostic record of what existed when the session was saved.
+func LoadSession(r io.Reader, opts ...SessionOption) Action {
+	return ActionFunc(func(ctx context.Context) error {
+		var sess Session
+		if err := json.NewDecoder(r).Decode(&sess); err != nil {
+			return err
+		}
+		if sess.Version != sessionVersion {
+			return fmt.Errorf("chromedp: session envelope version %d is not supported (want %d)", sess.Version, sessionVersion)
+		}
+
+		params := CookieParamsFromCookies(sess.Cookies)
+		if err := network.SetCookies(params).Do(ctx); err != nil {
+			return err
+		}
+
+		for _, os := range sess.Origins {
+			for _, entry := range os.LocalStorage {
+				if len(entry) != 2 {
+					continue
+				}
+				err := storage.SetDOMStorageItem(&storage.StorageID{
+					StorageKey:     os.StorageKey,
+					IsLocalStorage: true,
+				}, entry[0], entry[1]).Do(ctx)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
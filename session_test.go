@@ -0,0 +1,65 @@
+package chromedp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/page"
+)
+
+func TestCollectFramesFlattensTree(t *testing.T) {
+	tree := &page.FrameTree{
+		Frame: &cdp.Frame{ID: cdp.FrameID("root"), SecurityOrigin: "https://root.example"},
+		ChildFrames: []*page.FrameTree{
+			{
+				Frame: &cdp.Frame{ID: cdp.FrameID("child-a")},
+			},
+			{
+				Frame: &cdp.Frame{ID: cdp.FrameID("child-b")},
+				ChildFrames: []*page.FrameTree{
+					{Frame: &cdp.Frame{ID: cdp.FrameID("grandchild")}},
+				},
+			},
+		},
+	}
+
+	frames := collectFrames(tree)
+
+	want := []cdp.FrameID{"root", "child-a", "child-b", "grandchild"}
+	if len(frames) != len(want) {
+		t.Fatalf("got %v, want %v", frames, want)
+	}
+	for i, id := range want {
+		if frames[i].ID != id {
+			t.Fatalf("got %v, want %v", frames, want)
+		}
+	}
+	if frames[0].SecurityOrigin != "https://root.example" {
+		t.Fatalf("got root SecurityOrigin %q, want https://root.example", frames[0].SecurityOrigin)
+	}
+}
+
+func TestCollectFramesHandlesNilFrame(t *testing.T) {
+	if frames := collectFrames(nil); frames != nil {
+		t.Fatalf("got %v, want nil", frames)
+	}
+	if frames := collectFrames(&page.FrameTree{}); frames != nil {
+		t.Fatalf("got %v, want nil", frames)
+	}
+}
+
+func TestLoadSessionRejectsMismatchedVersion(t *testing.T) {
+	body, err := json.Marshal(&Session{Version: sessionVersion + 1})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	err = LoadSession(bytes.NewReader(body)).Do(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "version") {
+		t.Fatalf("got err %v, want an error mentioning the session version", err)
+	}
+}
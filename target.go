@@ -0,0 +1,302 @@
+package chromedp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/target"
+	"github.com/mailru/easyjson"
+)
+
+// TargetAction pairs an Action with the context it should run against, so
+// WaitOneOfTargets can race actions scoped to different targets - for
+// example a login-popup handler against the main navigation - instead of
+// silently hanging when a click opens a new tab.
+type TargetAction struct {
+	Ctx    context.Context
+	Action Action
+}
+
+type targetSessionKey struct{}
+
+// attachedTarget is threaded through a context by AttachToTarget and NewTab
+// so that callers can recover which target and session a context is scoped
+// to.
+type attachedTarget struct {
+	targetID  target.ID
+	sessionID target.SessionID
+}
+
+// withAttachedTarget scopes ctx to at: AttachedTarget can recover at from it,
+// and cdp commands issued against the returned context are dispatched to
+// at's session via sessionExecutor, rather than whichever target ctx was
+// already bound to.
+func withAttachedTarget(ctx context.Context, at *attachedTarget) context.Context {
+	ctx = context.WithValue(ctx, targetSessionKey{}, at)
+	return cdp.WithExecutor(ctx, &sessionExecutor{sessionID: at.sessionID, parent: ctx})
+}
+
+// AttachedTarget returns the target and session IDs a context was scoped to
+// by AttachToTarget or NewTab, if any.
+func AttachedTarget(ctx context.Context) (targetID target.ID, sessionID target.SessionID, ok bool) {
+	at, ok := ctx.Value(targetSessionKey{}).(*attachedTarget)
+	if !ok {
+		return "", "", false
+	}
+	return at.targetID, at.sessionID, true
+}
+
+// AttachToTarget attaches to targetID - typically one discovered via
+// AutoAttachTargets' Target.attachedToTarget listener - and returns a
+// context scoped to it for use with WaitOneOfTargets.
+func AttachToTarget(ctx context.Context, targetID target.ID) (context.Context, error) {
+	sessionID, err := target.AttachToTarget(targetID).WithFlatten(false).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return withAttachedTarget(ctx, &attachedTarget{targetID: targetID, sessionID: sessionID}), nil
+}
+
+// AutoAttachTargets enables Target.setDiscoverTargets and Target.setAutoAttach,
+// and calls handler - with a context scoped to the new target - for every
+// Target.attachedToTarget event observed for the lifetime of ctx. This is
+// what lets a script race a login-popup handler against the main navigation
+// via WaitOneOfTargets without missing the popup's attach event.
+func AutoAttachTargets(handler func(ctx context.Context, info *target.EventAttachedToTarget)) Action {
+	return ActionFunc(func(ctx context.Context) error {
+		if err := target.SetDiscoverTargets(true).Do(ctx); err != nil {
+			return err
+		}
+		if err := target.SetAutoAttach(true, false).WithFlatten(false).Do(ctx); err != nil {
+			return err
+		}
+
+		ListenTarget(ctx, func(ev interface{}) {
+			e, ok := ev.(*target.EventAttachedToTarget)
+			if !ok {
+				return
+			}
+			attached := withAttachedTarget(ctx, &attachedTarget{
+				targetID:  e.TargetInfo.TargetID,
+				sessionID: e.SessionID,
+			})
+			handler(attached, e)
+		})
+
+		return nil
+	})
+}
+
+// NewTab opens a new browser tab navigated to urlstr and returns a context
+// scoped to it, along with the Action that creates and attaches to it. The
+// returned context only resolves to the new tab once the Action has run, so
+// it must be run - directly, or as part of a TargetAction via
+// WaitOneOfTargets - before any other Action is issued against it.
+func NewTab(urlstr string, opts ...NavigateOption) (context.Context, Action) {
+	tab := &tabContext{}
+
+	act := ActionFunc(func(ctx context.Context) error {
+		targetID, err := target.CreateTarget(urlstr).Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		attached, err := AttachToTarget(ctx, targetID)
+		if err != nil {
+			return err
+		}
+
+		tab.set(attached)
+		return waitNavEvent(attached, opts...)
+	})
+
+	return tab, act
+}
+
+// tabContext is a context.Context that starts out empty and is filled in by
+// NewTab's Action once the target it creates has been attached to, so that
+// NewTab can hand back a context before that attachment happens.
+type tabContext struct {
+	mu       sync.Mutex
+	resolved context.Context
+}
+
+func (t *tabContext) set(ctx context.Context) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.resolved = ctx
+}
+
+func (t *tabContext) get() context.Context {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.resolved == nil {
+		return context.Background()
+	}
+	return t.resolved
+}
+
+func (t *tabContext) Deadline() (deadline time.Time, ok bool) { return t.get().Deadline() }
+func (t *tabContext) Done() <-chan struct{}                   { return t.get().Done() }
+func (t *tabContext) Err() error                              { return t.get().Err() }
+func (t *tabContext) Value(key interface{}) interface{}       { return t.get().Value(key) }
+
+// sessionExecutor implements cdp.Executor by addressing every command to a
+// specific attached session via Target.sendMessageToTarget/
+// Target.receivedMessageFromTarget, so actions run against a context
+// returned by AttachToTarget, AutoAttachTargets or NewTab are dispatched to
+// that session instead of whichever executor the parent context was bound
+// to.
+type sessionExecutor struct {
+	sessionID target.SessionID
+	// parent is the context the session was attached from; its executor
+	// carries the Target.sendMessageToTarget calls.
+	parent context.Context
+}
+
+var sessionExecutorMsgID int64
+
+func (e *sessionExecutor) Execute(ctx context.Context, method string, params easyjson.Marshaler, res easyjson.Unmarshaler) error {
+	id := atomic.AddInt64(&sessionExecutorMsgID, 1)
+
+	var rawParams json.RawMessage
+	if params != nil {
+		b, err := easyjson.Marshal(params)
+		if err != nil {
+			return err
+		}
+		rawParams = b
+	}
+
+	req, err := json.Marshal(struct {
+		ID     int64           `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params,omitempty"`
+	}{ID: id, Method: method, Params: rawParams})
+	if err != nil {
+		return err
+	}
+
+	replyC := make(chan string, 1)
+	lctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ListenTarget(lctx, func(ev interface{}) {
+		re, ok := ev.(*target.EventReceivedMessageFromTarget)
+		if !ok || re.SessionID != e.sessionID {
+			return
+		}
+
+		var probe struct {
+			ID int64 `json:"id"`
+		}
+		if json.Unmarshal([]byte(re.Message), &probe) != nil || probe.ID != id {
+			return
+		}
+
+		select {
+		case replyC <- re.Message:
+		default:
+		}
+	})
+
+	if err := target.SendMessageToTarget(string(req)).WithSessionID(e.sessionID).Do(e.parent); err != nil {
+		return err
+	}
+
+	select {
+	case msg := <-replyC:
+		var reply struct {
+			Result json.RawMessage `json:"result"`
+			Error  *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(msg), &reply); err != nil {
+			return err
+		}
+		if reply.Error != nil {
+			return errors.New(reply.Error.Message)
+		}
+		if res != nil && len(reply.Result) > 0 {
+			return easyjson.Unmarshal(reply.Result, res)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitOneOfTargets races actions, each against its own target-scoped
+// context, returning as soon as the first one completes; if that action
+// failed, its error is returned. On success, waitIdx (if non-nil) is set to
+// the index of the winning TargetAction. The
+// per-action contexts derived here are canceled as soon as a winner is
+// picked, so a losing action - e.g. a popup watcher with nothing left to
+// wait for - doesn't keep WaitOneOfTargets from returning.
+func WaitOneOfTargets(waitIdx *int, actions ...TargetAction) Action {
+	if len(actions) == 0 {
+		panic("actions cannot be empty")
+	}
+
+	return ActionFunc(func(ctx context.Context) error {
+		wg := &sync.WaitGroup{}
+		defer wg.Wait()
+
+		cancels := make([]context.CancelFunc, len(actions))
+		defer func() {
+			for _, cancel := range cancels {
+				cancel()
+			}
+		}()
+
+		type ret struct {
+			idx int
+			err error
+		}
+		retC := make(chan ret)
+
+		for idx := range actions {
+			ta := actions[idx]
+			actionCtx, cancel := context.WithCancel(ta.Ctx)
+			cancels[idx] = cancel
+
+			wg.Add(1)
+			go func(idx int, ta TargetAction, actionCtx context.Context) {
+				defer wg.Done()
+
+				err := ta.Action.Do(actionCtx)
+				select {
+				case retC <- ret{idx: idx, err: err}:
+				case <-actionCtx.Done():
+				}
+			}(idx, ta, actionCtx)
+		}
+
+		select {
+		case r := <-retC:
+			// Cancel every action's context now, before the deferred
+			// wg.Wait() blocks on them returning, so losers stop instead of
+			// running until their own ctx/events tell them to.
+			for _, cancel := range cancels {
+				cancel()
+			}
+			if r.err != nil {
+				return r.err
+			}
+			if waitIdx != nil {
+				*waitIdx = r.idx
+			}
+			return nil
+		case <-ctx.Done():
+			for _, cancel := range cancels {
+				cancel()
+			}
+			return ctx.Err()
+		}
+	})
+}
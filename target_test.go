@@ -0,0 +1,86 @@
+package chromedp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingAction blocks until its context is canceled, then reports whether
+// it observed the cancellation (as opposed to being left running forever).
+type blockingAction struct {
+	canceled chan<- struct{}
+}
+
+func (a blockingAction) Do(ctx context.Context) error {
+	<-ctx.Done()
+	a.canceled <- struct{}{}
+	return ctx.Err()
+}
+
+func TestWaitOneOfTargetsReturnsFirstWinner(t *testing.T) {
+	var idx int
+	err := WaitOneOfTargets(&idx,
+		TargetAction{Ctx: context.Background(), Action: ActionFunc(func(ctx context.Context) error {
+			<-time.After(50 * time.Millisecond)
+			return errors.New("should have lost the race")
+		})},
+		TargetAction{Ctx: context.Background(), Action: ActionFunc(func(ctx context.Context) error {
+			return nil
+		})},
+	).Do(context.Background())
+
+	if err != nil {
+		t.Fatalf("WaitOneOfTargets returned error: %v", err)
+	}
+	if idx != 1 {
+		t.Fatalf("got winning index %d, want 1", idx)
+	}
+}
+
+func TestWaitOneOfTargetsCancelsLosers(t *testing.T) {
+	canceled := make(chan struct{}, 1)
+
+	err := WaitOneOfTargets(nil,
+		TargetAction{Ctx: context.Background(), Action: blockingAction{canceled: canceled}},
+		TargetAction{Ctx: context.Background(), Action: ActionFunc(func(ctx context.Context) error {
+			return nil
+		})},
+	).Do(context.Background())
+
+	if err != nil {
+		t.Fatalf("WaitOneOfTargets returned error: %v", err)
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatalf("losing action's context was never canceled")
+	}
+}
+
+func TestWaitOneOfTargetsPropagatesError(t *testing.T) {
+	wantErr := errors.New("boom")
+	err := WaitOneOfTargets(nil,
+		TargetAction{Ctx: context.Background(), Action: ActionFunc(func(ctx context.Context) error {
+			return wantErr
+		})},
+	).Do(context.Background())
+
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("got error %v, want %v", err, wantErr)
+	}
+}
+
+func TestAttachedTargetRoundTrips(t *testing.T) {
+	if _, _, ok := AttachedTarget(context.Background()); ok {
+		t.Fatalf("found an attached target in a plain context.Background()")
+	}
+
+	ctx := withAttachedTarget(context.Background(), &attachedTarget{targetID: "tid", sessionID: "sid"})
+	targetID, sessionID, ok := AttachedTarget(ctx)
+	if !ok || targetID != "tid" || sessionID != "sid" {
+		t.Fatalf("got targetID=%q sessionID=%q ok=%v, want tid sid true", targetID, sessionID, ok)
+	}
+}
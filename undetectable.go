@@ -2,7 +2,11 @@ package chromedp
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"sync"
 
+	"github.com/chromedp/cdproto/emulation"
 	"github.com/chromedp/cdproto/page"
 )
 
@@ -18,46 +22,52 @@ import (
 		chromedp.CaptureScreenshot(&buf),
 */
 
-type undetectableOptions struct {
-	bypassIframeTest bool // default true
-}
+// stealthPatches is the registry of named scripts that Undetectable can be
+// asked to apply via WithPatches. RegisterStealthPatch lets callers add or
+// override entries without touching this file.
+var stealthPatches = struct {
+	sync.RWMutex
+	m map[string]string
+}{m: make(map[string]string)}
 
-func newUndetectableOptions() *undetectableOptions {
-	return &undetectableOptions{
-		bypassIframeTest: true,
-	}
+// RegisterStealthPatch registers the JavaScript snippet to run for a named
+// stealth patch, overwriting any existing patch of the same name. Pass the
+// name to Undetectable's WithPatches option to include it. "canvas-noise" is
+// the exception: Undetectable always renders that patch from
+// canvasNoisePatch with its configured seed, so a script registered under
+// that name is not used.
+func RegisterStealthPatch(name, script string) {
+	stealthPatches.Lock()
+	defer stealthPatches.Unlock()
+	stealthPatches.m[name] = script
 }
 
-type UndetectableOption = func(*undetectableOptions)
+func stealthPatch(name string) (string, bool) {
+	stealthPatches.RLock()
+	defer stealthPatches.RUnlock()
+	s, ok := stealthPatches.m[name]
+	return s, ok
+}
 
-func BypassIframeTest(bypassIframeTest bool) UndetectableOption {
-	return func(opts *undetectableOptions) {
-		opts.bypassIframeTest = bypassIframeTest
-	}
+// defaultStealthPatches lists the patches Undetectable applies when called
+// without WithPatches, matching its historical behavior.
+var defaultStealthPatches = []string{
+	"webdriver", "plugins", "languages", "chrome-runtime", "permissions",
+	"webgl", "broken-image", "hairline", "iframe",
 }
 
-func Undetectable(opts ...UndetectableOption) Action {
-	script := `
-	(function (window, navigator) {
-		delete navigator.__proto__.webdriver;
-	
-		// The method below cant bypass "'webdriver' in navigator", so we remove it
-		// // Pass the Webdriver Test.
-		// const originHasOwnProperty = navigator.hasOwnProperty;
-		// navigator.hasOwnProperty = (property) => (
-		// 	property === 'webdriver' ? false : originHasOwnProperty(property)
-		// );
-	
-		// The method below cant bypass "'webdriver' in navigator", so we remove it
-		// Object.defineProperty(navigator, 'webdriver', {
-		//   get: () => undefined,
-		// });
-
-		// This value was be null when using chrome-driver
-		Object.defineProperty(navigator, 'doNotTrack', {
-			get: () => '1',
-		});
-	
+func init() {
+	RegisterStealthPatch("webdriver", `
+	delete navigator.__proto__.webdriver;
+
+	// This value was be null when using chrome-driver
+	Object.defineProperty(navigator, 'doNotTrack', {
+		get: () => '1',
+	});
+	`)
+
+	RegisterStealthPatch("plugins", `
+	(function (navigator) {
 		// Pass the Plugins Length Test.
 		// Overwrite the plugins property to use a custom getter.
 		udNewPlugin = function(prot, mimes) {
@@ -82,10 +92,6 @@ func Undetectable(opts ...UndetectableOption) Action {
 				Object.setPrototypeOf(res, prot);
 				return res;
 			})();
-			// doesn't need
-			// for (let i = 0; i < mimes.length; i++) {
-			// 	mimes[i].enabledPlugin = p
-			// }
 			return p
 		}
 		udMimes = [
@@ -139,31 +145,37 @@ func Undetectable(opts ...UndetectableOption) Action {
 				return udPlugins;
 			},
 		});
+	})(navigator);
+	`)
 
-		// Pass the Languages Test.
-		// Overwrite the languages property to use a custom getter.
-		Object.defineProperty(navigator, 'languages', {
-			get: () => ['en-US', 'en'],
-		});
-	
-		// Pass the Chrome Test.
-		// We can mock this in as much depth as we need for the test.
-		window.chrome = {
-			runtime: {},
-		};
-	
-		// Pass the Permissions Test.
-		const originalQuery = window.navigator.permissions.query;
-		return window.navigator.permissions.query = (parameters) => (
-			parameters.name === 'notifications' ?
-				Promise.resolve({ state: Notification.permission }) :
-				originalQuery(parameters)
-		);
-	})(window, navigator, window.navigator);
-	
-	//
+	RegisterStealthPatch("languages", `
+	// Pass the Languages Test.
+	// Overwrite the languages property to use a custom getter.
+	Object.defineProperty(navigator, 'languages', {
+		get: () => ['en-US', 'en'],
+	});
+	`)
+
+	RegisterStealthPatch("chrome-runtime", `
+	// Pass the Chrome Test.
+	// We can mock this in as much depth as we need for the test.
+	window.chrome = {
+		runtime: {},
+	};
+	`)
+
+	RegisterStealthPatch("permissions", `
+	// Pass the Permissions Test.
+	const originalQuery = window.navigator.permissions.query;
+	window.navigator.permissions.query = (parameters) => (
+		parameters.name === 'notifications' ?
+			Promise.resolve({ state: Notification.permission }) :
+			originalQuery(parameters)
+	);
+	`)
+
+	RegisterStealthPatch("webgl", `
 	// Bypass the WebGL test.
-	//
 	const originalGetParameter = WebGLRenderingContext.getParameter;
 	WebGLRenderingContext.prototype.getParameter = function (parameter) {
 		// UNMASKED_VENDOR_WEBGL
@@ -174,20 +186,16 @@ func Undetectable(opts ...UndetectableOption) Action {
 		if (parameter === 37446) {
 			return 'Mesa DRI Intel(R) Ivybridge Mobile ';
 		}
-	
+
 		return originalGetParameter(parameter);
 	};
-	
-	
-	//
+	`)
+
+	RegisterStealthPatch("broken-image", `
 	// Bypass the Broken Image Test.
-	//
-	
 	['height', 'width'].forEach(property => {
-		// Store the existing descriptor.
 		const imageDescriptor = Object.getOwnPropertyDescriptor(HTMLImageElement.prototype, property);
-	
-		// Redefine the property with a patched descriptor.
+
 		Object.defineProperty(HTMLImageElement.prototype, property, {
 			...imageDescriptor,
 			get: function () {
@@ -195,21 +203,16 @@ func Undetectable(opts ...UndetectableOption) Action {
 				if (this.complete && this.naturalHeight == 0) {
 					return 20;
 				}
-				// Otherwise, return the actual dimension.
 				return imageDescriptor.get.apply(this);
 			},
 		});
 	});
-	
-	
-	//
+	`)
+
+	RegisterStealthPatch("hairline", `
 	// Bypass the Retina/HiDPI Hairline Feature Test.
-	//
-	
-	// Store the existing descriptor.
 	const elementDescriptor = Object.getOwnPropertyDescriptor(HTMLElement.prototype, 'offsetHeight');
-	
-	// Redefine the property with a patched descriptor.
+
 	Object.defineProperty(HTMLDivElement.prototype, 'offsetHeight', {
 		...elementDescriptor,
 		get: function () {
@@ -219,28 +222,268 @@ func Undetectable(opts ...UndetectableOption) Action {
 			return elementDescriptor.get.apply(this);
 		},
 	});
+	`)
 
-	  `
-	options := newUndetectableOptions()
-	for _, opt := range opts {
-		opt(options)
-	}
-
-	if options.bypassIframeTest {
-		script = script + `
-// Pass the iframe Test
+	RegisterStealthPatch("iframe", `
+	// Pass the iframe Test
 	Object.defineProperty(HTMLIFrameElement.prototype, 'contentWindow', {
 		get: function() {
 		  return window;
 		}
 	});
-`
+	`)
+
+	RegisterStealthPatch("canvas-noise", canvasNoisePatch(1))
+
+	RegisterStealthPatch("audio-noise", `
+	// Perturb AudioContext fingerprinting by adding a small amount of noise
+	// to every channel read back from the buffer. getChannelData returns a
+	// live view onto the buffer's backing storage, so perturb a copy rather
+	// than the array it hands back - otherwise every read re-adds noise on
+	// top of the last, corrupting real audio processing in the page.
+	const originalGetChannelData = AudioBuffer.prototype.getChannelData;
+	AudioBuffer.prototype.getChannelData = function (channel) {
+		const data = originalGetChannelData.apply(this, [channel]);
+		const copy = Float32Array.from(data);
+		for (let i = 0; i < copy.length; i += 100) {
+			copy[i] = copy[i] + (Math.sin(i) * 1e-7);
+		}
+		return copy;
+	};
+	`)
+
+	RegisterStealthPatch("hardwareconcurrency", `
+	// Spoof navigator.hardwareConcurrency/deviceMemory to common, less
+	// identifying values.
+	Object.defineProperty(navigator, 'hardwareConcurrency', {
+		get: () => 4,
+	});
+	Object.defineProperty(navigator, 'deviceMemory', {
+		get: () => 8,
+	});
+	`)
+
+	RegisterStealthPatch("battery", `
+	// Pass the Battery API Test with a plausible, always-charged battery.
+	navigator.getBattery = () => Promise.resolve({
+		charging: true,
+		chargingTime: 0,
+		dischargingTime: Infinity,
+		level: 1,
+		addEventListener: () => {},
+		removeEventListener: () => {},
+	});
+	`)
+
+	RegisterStealthPatch("webrtc", `
+	// Block the WebRTC local-IP leak by stripping host candidates from the
+	// SDP/ICE candidates surfaced to the page.
+	const OriginalRTCPeerConnection = window.RTCPeerConnection;
+	if (OriginalRTCPeerConnection) {
+		window.RTCPeerConnection = function (...args) {
+			const pc = new OriginalRTCPeerConnection(...args);
+			const originalAddEventListener = pc.addEventListener.bind(pc);
+			pc.addEventListener = function (type, listener, ...rest) {
+				if (type !== 'icecandidate') {
+					return originalAddEventListener(type, listener, ...rest);
+				}
+				return originalAddEventListener(type, (ev) => {
+					if (ev.candidate && ev.candidate.candidate && ev.candidate.candidate.includes('host')) {
+						return;
+					}
+					listener(ev);
+				}, ...rest);
+			};
+			return pc;
+		};
+		window.RTCPeerConnection.prototype = OriginalRTCPeerConnection.prototype;
 	}
+	`)
+}
+
+// canvasNoisePatch renders the canvas-noise patch script, perturbing
+// toDataURL/getImageData pixels by a deterministic amount derived from seed
+// so that repeated calls from the same profile produce a stable, but
+// uniquely-identifying-resistant, canvas fingerprint.
+func canvasNoisePatch(seed int64) string {
+	return fmt.Sprintf(`
+	(function () {
+		const seed = %d;
+		function noiseAt(i) {
+			return ((Math.sin(i * 12.9898 + seed) * 43758.5453) % 1) * 2 - 1;
+		}
+
+		const originalToDataURL = HTMLCanvasElement.prototype.toDataURL;
+		HTMLCanvasElement.prototype.toDataURL = function (...args) {
+			const ctx = this.getContext('2d');
+			if (ctx) {
+				// Draw onto a detached copy so the noise never touches the
+				// page's own, possibly visible, canvas.
+				const copy = document.createElement('canvas');
+				copy.width = this.width;
+				copy.height = this.height;
+				const copyCtx = copy.getContext('2d');
+				copyCtx.drawImage(this, 0, 0);
+
+				const imageData = copyCtx.getImageData(0, 0, copy.width, copy.height);
+				for (let i = 0; i < imageData.data.length; i += 4) {
+					imageData.data[i] = imageData.data[i] + noiseAt(i);
+				}
+				copyCtx.putImageData(imageData, 0, 0);
+
+				return originalToDataURL.apply(copy, args);
+			}
+			return originalToDataURL.apply(this, args);
+		};
+
+		const originalGetImageData = CanvasRenderingContext2D.prototype.getImageData;
+		CanvasRenderingContext2D.prototype.getImageData = function (...args) {
+			const imageData = originalGetImageData.apply(this, args);
+			for (let i = 0; i < imageData.data.length; i += 4) {
+				imageData.data[i] = imageData.data[i] + noiseAt(i);
+			}
+			return imageData;
+		};
+	})();
+	`, seed)
+}
+
+// defaultCanvasNoiseSeed is the seed canvas-noise uses when the caller
+// doesn't supply one via WithCanvasNoiseSeed, matching its historical
+// behavior from before the seed was made configurable.
+const defaultCanvasNoiseSeed = 1
+
+type undetectableOptions struct {
+	patches         []string
+	clientHints     *emulation.UserAgentMetadata
+	canvasNoiseSeed int64
+}
+
+// UndetectableOption is an Undetectable action option.
+type UndetectableOption = func(*undetectableOptions)
+
+// WithPatches selects exactly the named stealth patches to apply, in place
+// of Undetectable's default set. Names are looked up in the registry
+// populated by RegisterStealthPatch.
+func WithPatches(names ...string) UndetectableOption {
+	return func(opts *undetectableOptions) {
+		opts.patches = names
+	}
+}
+
+// BypassIframeTest toggles the "iframe" patch on Undetectable's default
+// patch set.
+//
+// Deprecated: use WithPatches and include or omit "iframe" explicitly.
+func BypassIframeTest(bypassIframeTest bool) UndetectableOption {
+	return func(opts *undetectableOptions) {
+		filtered := make([]string, 0, len(opts.patches))
+		for _, p := range opts.patches {
+			if p != "iframe" {
+				filtered = append(filtered, p)
+			}
+		}
+		if bypassIframeTest {
+			filtered = append(filtered, "iframe")
+		}
+		opts.patches = filtered
+	}
+}
+
+// WithCanvasNoiseSeed sets the seed the "canvas-noise" patch derives its
+// per-pixel noise from, instead of sharing the single seed baked into the
+// registry's default canvas-noise script. Every chromedp process using the
+// default seed produces byte-identical canvas noise, which is itself a
+// stable fingerprint; give each profile its own seed to avoid that.
+func WithCanvasNoiseSeed(seed int64) UndetectableOption {
+	return func(opts *undetectableOptions) {
+		opts.canvasNoiseSeed = seed
+	}
+}
+
+// WithUserAgentClientHints patches navigator.userAgentData to report brands,
+// platform and mobile, and issues a matching Emulation.setUserAgentOverride
+// with userAgentMetadata set, since sites increasingly check the Client
+// Hints API instead of (or in addition to) the User-Agent string.
+func WithUserAgentClientHints(brands []*emulation.UserAgentBrandVersion, platform string, mobile bool) UndetectableOption {
+	return func(opts *undetectableOptions) {
+		opts.clientHints = &emulation.UserAgentMetadata{
+			Brands:   brands,
+			Platform: platform,
+			Mobile:   mobile,
+		}
+	}
+}
+
+// Undetectable patches a handful of well-known headless-Chrome tells -
+// described in https://intoli.com/blog/making-chrome-headless-undetectable/
+// and https://antoinevastel.com/bots/ - by default. Use WithPatches to
+// select a different set from the registry populated by
+// RegisterStealthPatch, or RegisterStealthPatch itself to add new ones.
+func Undetectable(opts ...UndetectableOption) Action {
+	options := &undetectableOptions{patches: defaultStealthPatches, canvasNoiseSeed: defaultCanvasNoiseSeed}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	var scripts []string
+	for _, name := range options.patches {
+		if name == "canvas-noise" {
+			scripts = append(scripts, canvasNoisePatch(options.canvasNoiseSeed))
+			continue
+		}
+		script, ok := stealthPatch(name)
+		if !ok {
+			continue
+		}
+		scripts = append(scripts, script)
+	}
+	script := strings.Join(scripts, "\n")
 
 	return ActionFunc(func(ctx context.Context) error {
+		if options.clientHints != nil {
+			var ua string
+			if err := EvaluateAsDevTools(`navigator.userAgent`, &ua).Do(ctx); err != nil {
+				return err
+			}
+
+			act := emulation.SetUserAgentOverride(ua).
+				WithUserAgentMetadata(options.clientHints)
+			if err := act.Do(ctx); err != nil {
+				return err
+			}
+
+			hints := fmt.Sprintf(`
+			Object.defineProperty(navigator, 'userAgentData', {
+				get: () => ({
+					brands: %s,
+					mobile: %t,
+					platform: %q,
+					getHighEntropyValues: (hints) => Promise.resolve({
+						brands: %s,
+						mobile: %t,
+						platform: %q,
+					}),
+				}),
+			});
+			`, brandsToJS(options.clientHints.Brands), options.clientHints.Mobile, options.clientHints.Platform,
+				brandsToJS(options.clientHints.Brands), options.clientHints.Mobile, options.clientHints.Platform)
+			if _, err := page.AddScriptToEvaluateOnNewDocument(hints).Do(ctx); err != nil {
+				return err
+			}
+		}
+
 		if _, err := page.AddScriptToEvaluateOnNewDocument(script).Do(ctx); err != nil {
 			return err
 		}
 		return nil
 	})
 }
+
+func brandsToJS(brands []*emulation.UserAgentBrandVersion) string {
+	parts := make([]string, 0, len(brands))
+	for _, b := range brands {
+		parts = append(parts, fmt.Sprintf(`{brand: %q, version: %q}`, b.Brand, b.Version))
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
@@ -0,0 +1,98 @@
+package chromedp
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRegisterStealthPatchOverridesAndLooksUp(t *testing.T) {
+	RegisterStealthPatch("test-patch", "// original")
+	if script, ok := stealthPatch("test-patch"); !ok || script != "// original" {
+		t.Fatalf("got (%q, %v), want (%q, true)", script, ok, "// original")
+	}
+
+	RegisterStealthPatch("test-patch", "// replaced")
+	if script, ok := stealthPatch("test-patch"); !ok || script != "// replaced" {
+		t.Fatalf("got (%q, %v), want (%q, true)", script, ok, "// replaced")
+	}
+
+	if _, ok := stealthPatch("does-not-exist"); ok {
+		t.Fatalf("stealthPatch found a script for an unregistered name")
+	}
+}
+
+func TestWithPatchesOverridesDefaultSet(t *testing.T) {
+	options := &undetectableOptions{patches: defaultStealthPatches}
+	WithPatches("webdriver", "canvas-noise")(options)
+
+	if len(options.patches) != 2 || options.patches[0] != "webdriver" || options.patches[1] != "canvas-noise" {
+		t.Fatalf("got patches %v, want [webdriver canvas-noise]", options.patches)
+	}
+}
+
+func TestBypassIframeTestTogglesIframePatch(t *testing.T) {
+	options := &undetectableOptions{patches: []string{"webdriver", "iframe"}}
+
+	BypassIframeTest(false)(options)
+	for _, p := range options.patches {
+		if p == "iframe" {
+			t.Fatalf("got patches %v, want no iframe patch", options.patches)
+		}
+	}
+
+	BypassIframeTest(true)(options)
+	found := false
+	for _, p := range options.patches {
+		if p == "iframe" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("got patches %v, want iframe patch present", options.patches)
+	}
+}
+
+func TestWithCanvasNoiseSeedOverridesDefault(t *testing.T) {
+	options := &undetectableOptions{canvasNoiseSeed: defaultCanvasNoiseSeed}
+	WithCanvasNoiseSeed(99)(options)
+
+	if options.canvasNoiseSeed != 99 {
+		t.Fatalf("got canvasNoiseSeed %d, want 99", options.canvasNoiseSeed)
+	}
+}
+
+func TestAudioNoisePatchCopiesBeforeMutating(t *testing.T) {
+	script, ok := stealthPatch("audio-noise")
+	if !ok {
+		t.Fatalf("audio-noise patch not registered")
+	}
+
+	if !strings.Contains(script, "Float32Array.from(data)") {
+		t.Fatalf("audio-noise patch does not copy the channel data before perturbing it:\n%s", script)
+	}
+	if strings.Contains(script, "data[i] = data[i] +") {
+		t.Fatalf("audio-noise patch still mutates the live channel data in place:\n%s", script)
+	}
+}
+
+func TestCanvasNoisePatchEmbedsSeedAndAvoidsMutatingLiveCanvas(t *testing.T) {
+	script := canvasNoisePatch(42)
+
+	if !strings.Contains(script, "const seed = "+strconv.Itoa(42)+";") {
+		t.Fatalf("canvasNoisePatch(42) did not embed the seed:\n%s", script)
+	}
+
+	// The toDataURL patch must draw noise into a detached copy and call
+	// toDataURL on that copy, never call putImageData on the live canvas's
+	// own 2d context.
+	if strings.Contains(script, "ctx.putImageData") {
+		t.Fatalf("canvasNoisePatch calls putImageData on the live canvas context:\n%s", script)
+	}
+	if !strings.Contains(script, "copyCtx.putImageData") {
+		t.Fatalf("canvasNoisePatch does not draw noise onto a detached copy:\n%s", script)
+	}
+	if !strings.Contains(script, "originalToDataURL.apply(copy, args)") {
+		t.Fatalf("canvasNoisePatch does not call toDataURL on the detached copy:\n%s", script)
+	}
+}